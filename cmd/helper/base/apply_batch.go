@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// applyPrecedence is the fixed kind order a batch of objects is applied in, so
+// that dependent objects (e.g. a Deployment referencing a ServiceAccount) are
+// never installed before what they depend on. Kinds not listed are applied last,
+// after everything here, in the order they were given.
+var applyPrecedence = []string{
+	"Namespace",
+	"ResourceQuota",
+	"LimitRange",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"Endpoints",
+	"PersistentVolumeClaim",
+	"Deployment",
+	"DaemonSet",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Pod",
+}
+
+var applyPrecedenceRank = func() map[string]int {
+	ranks := make(map[string]int, len(applyPrecedence))
+	for i, kind := range applyPrecedence {
+		ranks[kind] = i
+	}
+
+	return ranks
+}()
+
+const unknownKindRank = len(applyPrecedence)
+
+// WorkloadApplyBatch installs objects on the workload cluster in dependency order
+// (see applyPrecedence) via server-side apply (WorkloadApply), waiting for any
+// CustomResourceDefinition to become Established (and have its names accepted)
+// before moving on to whatever might use it. It returns on the first error,
+// leaving the remaining objects in the batch unapplied for this cluster.
+func (t *Tool) WorkloadApplyBatch(
+	ctx context.Context,
+	c *clusterv1.Cluster,
+	objects []controllerutil.Object,
+	fieldManager string,
+) error {
+	ordered, err := t.orderForApply(objects)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range ordered {
+		if err := t.WorkloadApply(ctx, c, obj, fieldManager); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", ObjectToName(obj), err)
+		}
+
+		gvk, err := apiutil.GVKForObject(obj, t.scheme)
+		if err != nil {
+			return err
+		}
+
+		// Dispatch on the GVK rather than a concrete-type assertion, so CRDs
+		// supplied as *unstructured.Unstructured (e.g. from
+		// WorkloadApplyKustomization) still get waited on.
+		if gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		if err := t.WorkloadWait(ctx, c, obj, 0); err != nil {
+			return fmt.Errorf("failed waiting for CRD %s to become established: %w", ObjectToName(obj), err)
+		}
+	}
+
+	return nil
+}
+
+// WorkloadDeleteMany removes objects from the workload cluster in the reverse of
+// applyPrecedence, so dependents are torn down before what they depend on. It
+// returns on the first error, leaving the remaining objects in place.
+func (t *Tool) WorkloadDeleteMany(ctx context.Context, c *clusterv1.Cluster, objects []controllerutil.Object) error {
+	ordered, err := t.orderForApply(objects)
+	if err != nil {
+		return err
+	}
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if err := t.WorkloadDelete(ctx, c, ordered[i]); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", ObjectToName(ordered[i]), err)
+		}
+	}
+
+	return nil
+}
+
+func (t *Tool) orderForApply(objects []controllerutil.Object) ([]controllerutil.Object, error) {
+	// t.scheme, needed for GVKForObject below, is populated by ManagementClient.
+	if _, err := t.ManagementClient(); err != nil {
+		return nil, err
+	}
+
+	ordered := make([]controllerutil.Object, len(objects))
+	copy(ordered, objects)
+
+	ranks := make(map[controllerutil.Object]int, len(ordered))
+
+	for _, obj := range ordered {
+		gvk, err := apiutil.GVKForObject(obj, t.scheme)
+		if err != nil {
+			return nil, err
+		}
+
+		rank, ok := applyPrecedenceRank[gvk.Kind]
+		if !ok {
+			rank = unknownKindRank
+		}
+
+		ranks[obj] = rank
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ranks[ordered[i]] < ranks[ordered[j]]
+	})
+
+	return ordered, nil
+}
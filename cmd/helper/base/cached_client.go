@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	memcache "k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultCacheResyncPeriod is used when ToolConfig.CacheResyncPeriod is unset.
+const defaultCacheResyncPeriod = 10 * time.Minute
+
+// cachedClient pairs a client.Client backed by a controller-runtime cache with
+// the means to stop that cache's informers.
+type cachedClient struct {
+	client.Client
+
+	cancel context.CancelFunc
+}
+
+// newCachedClient builds a client.Client for restConfig that uses a memoized,
+// cached discovery RESTMapper (so repeated calls don't re-discover /api and
+// /apis) and an informer-backed cache (so repeated List/Get calls collapse into
+// a single watch). warmGVKs are given a GetInformer call up front so their
+// watches start immediately rather than on first use. The returned cachedClient
+// must be stopped once it is no longer needed.
+func newCachedClient(
+	ctx context.Context,
+	restConfig *rest.Config,
+	scheme *runtime.Scheme,
+	resyncPeriod time.Duration,
+	warmGVKs []schema.GroupVersionKind,
+) (*cachedClient, error) {
+	if resyncPeriod <= 0 {
+		resyncPeriod = defaultCacheResyncPeriod
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memcache.NewMemCacheClient(discoveryClient))
+
+	cacheCtx, cancel := context.WithCancel(ctx)
+
+	informerCache, err := cache.New(restConfig, cache.Options{ //nolint:exhaustivestruct
+		Scheme: scheme,
+		Mapper: mapper,
+		Resync: &resyncPeriod,
+	})
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	go func() {
+		_ = informerCache.Start(cacheCtx)
+	}()
+
+	if !informerCache.WaitForCacheSync(cacheCtx) {
+		cancel()
+
+		return nil, fmt.Errorf("failed to sync cache")
+	}
+
+	directClient, err := client.New(restConfig, client.Options{Scheme: scheme, Mapper: mapper}) //nolint:exhaustivestruct
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	delegating, err := client.NewDelegatingClient(client.NewDelegatingClientInput{ //nolint:exhaustivestruct
+		CacheReader: informerCache,
+		Client:      directClient,
+	})
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("failed to create delegating client: %w", err)
+	}
+
+	for _, gvk := range warmGVKs {
+		obj := new(unstructured.Unstructured)
+		obj.SetGroupVersionKind(gvk)
+
+		if _, err := informerCache.GetInformer(cacheCtx, obj); err != nil {
+			cancel()
+
+			return nil, fmt.Errorf("failed to warm cache for %s: %w", gvk, err)
+		}
+	}
+
+	return &cachedClient{Client: delegating, cancel: cancel}, nil
+}
+
+func (c *cachedClient) Stop() {
+	c.cancel()
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// DiffFormat selects how WorkloadPatchOrCreateUnstructured renders the difference
+// between the live and desired object when they do not match.
+type DiffFormat string
+
+const (
+	// DiffFormatYAML renders a unified diff of the redacted YAML representation of
+	// both objects. This is the default.
+	DiffFormatYAML DiffFormat = "YAML"
+
+	// DiffFormatJSONPatch renders an RFC 6902 JSON Patch from live to desired.
+	DiffFormatJSONPatch DiffFormat = "JSONPatch"
+
+	// DiffFormatMergePatch renders an RFC 7396 JSON Merge Patch from live to desired.
+	DiffFormatMergePatch DiffFormat = "MergePatch"
+)
+
+// renderDiff returns a human-readable, secret-redacted rendering of the change from
+// existing to desired in the format selected by format (DiffFormatYAML when empty).
+func renderDiff(format DiffFormat, existing, desired *unstructured.Unstructured) (string, error) {
+	switch format {
+	case DiffFormatJSONPatch:
+		return renderJSONPatchDiff(existing, desired)
+	case DiffFormatMergePatch:
+		return renderMergePatchDiff(existing, desired)
+	case DiffFormatYAML, "":
+		return renderYAMLDiff(existing, desired)
+	default:
+		return "", fmt.Errorf("unsupported diff format %q", format)
+	}
+}
+
+func renderYAMLDiff(existing, desired *unstructured.Unstructured) (string, error) {
+	beforeYAML, err := redactedYAML(existing)
+	if err != nil {
+		return "", err
+	}
+
+	afterYAML, err := redactedYAML(desired)
+	if err != nil {
+		return "", err
+	}
+
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(beforeYAML),
+		B:        difflib.SplitLines(afterYAML),
+		FromFile: "live",
+		ToFile:   "desired",
+		Context:  3,
+	}
+
+	out, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return "", fmt.Errorf("failed to render diff: %w", err)
+	}
+
+	return out, nil
+}
+
+func renderJSONPatchDiff(existing, desired *unstructured.Unstructured) (string, error) {
+	before, after, err := redactedJSON(existing, desired)
+	if err != nil {
+		return "", err
+	}
+
+	ops, err := jsonpatch.CreatePatch(before, after)
+	if err != nil {
+		return "", fmt.Errorf("failed to create json patch: %w", err)
+	}
+
+	out, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to format json patch: %w", err)
+	}
+
+	return string(out), nil
+}
+
+func renderMergePatchDiff(existing, desired *unstructured.Unstructured) (string, error) {
+	before, after, err := redactedJSON(existing, desired)
+	if err != nil {
+		return "", err
+	}
+
+	patch, err := jsonpatch.CreateMergePatch(before, after)
+	if err != nil {
+		return "", fmt.Errorf("failed to create merge patch: %w", err)
+	}
+
+	var pretty interface{}
+	if err := json.Unmarshal(patch, &pretty); err != nil {
+		return "", fmt.Errorf("failed to decode merge patch: %w", err)
+	}
+
+	out, err := json.MarshalIndent(pretty, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to format merge patch: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// redactedJSON marshals existing and desired to JSON, with Secret data/stringData
+// values redacted, for use by the patch-based diff renderers.
+func redactedJSON(existing, desired *unstructured.Unstructured) ([]byte, []byte, error) {
+	redactedExisting := existing.DeepCopy()
+	redactedDesired := desired.DeepCopy()
+
+	if redactedExisting.GetKind() == "Secret" {
+		redactSecretData(redactedExisting)
+	}
+
+	if redactedDesired.GetKind() == "Secret" {
+		redactSecretData(redactedDesired)
+	}
+
+	before, err := yaml.Marshal(redactedExisting.Object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal live object: %w", err)
+	}
+
+	after, err := yaml.Marshal(redactedDesired.Object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal desired object: %w", err)
+	}
+
+	beforeJSON, err := yaml.YAMLToJSON(before)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert live object to json: %w", err)
+	}
+
+	afterJSON, err := yaml.YAMLToJSON(after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert desired object to json: %w", err)
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+// mergeUnstructured recursively merges src into dst, overwriting scalar and list
+// values but merging nested maps key by key.
+func mergeUnstructured(dst, src map[string]interface{}) {
+	for key, srcValue := range src {
+		dstValue, ok := dst[key]
+		if !ok {
+			dst[key] = srcValue
+
+			continue
+		}
+
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+
+		if dstIsMap && srcIsMap {
+			mergeUnstructured(dstMap, srcMap)
+
+			continue
+		}
+
+		dst[key] = srcValue
+	}
+}
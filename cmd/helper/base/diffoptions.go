@@ -0,0 +1,188 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultDiffContextLines is used when DiffOptions.ContextLines is unset (zero).
+const defaultDiffContextLines = 3
+
+// alwaysPrunedFields are dropped from every server-side dry-run diff regardless
+// of DiffOptions, because they are never meaningful to a human comparing two
+// revisions of the same object.
+var alwaysPrunedFields = [][]string{
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+}
+
+// DiffOptions controls how WorkloadPatch, WorkloadCreate and WorkloadDelete
+// render the server-side dry-run diff they show under DryRun().
+type DiffOptions struct {
+	// ContextLines is the number of unchanged lines of context shown around each
+	// change. Defaults to defaultDiffContextLines when zero.
+	ContextLines int
+
+	// NoColor disables ANSI coloring of added/removed lines even when the
+	// configured OutputSink would otherwise support it.
+	NoColor bool
+
+	// IncludeStatus keeps the object's status subresource in the diff. Status is
+	// pruned by default since it is populated asynchronously by controllers and
+	// rarely reflects what a dry-run patch/create/delete itself changed.
+	IncludeStatus bool
+
+	// PruneFields are additional dot-separated paths (e.g. "spec.replicas") to
+	// drop from both sides of the diff before rendering.
+	PruneFields []string
+}
+
+// shouldColorize reports whether renderServerDiff should wrap +/- lines in ANSI
+// color. sinkSupportsColor reflects the Tool's configured OutputSink, not a
+// blanket stdout check, so diffs routed through a structured sink such as
+// JSONLSink or FileSink are never colorized even when stdout happens to be a
+// terminal.
+func (o DiffOptions) shouldColorize(sinkSupportsColor bool) bool {
+	if o.NoColor {
+		return false
+	}
+
+	return sinkSupportsColor
+}
+
+// fetchForDiff returns the canonical "before" state of obj for a dry-run diff:
+// the live object on the workload cluster, or an empty unstructured object if it
+// does not exist (e.g. prior to a Create).
+func fetchForDiff(ctx context.Context, workloadClient client.Client, key client.ObjectKey, obj runtime.Object) (*unstructured.Unstructured, error) {
+	existing := obj.DeepCopyObject()
+
+	if err := workloadClient.Get(ctx, key, existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return new(unstructured.Unstructured), nil
+		}
+
+		return nil, err
+	}
+
+	return toUnstructured(existing)
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert object for diffing: %w", err)
+	}
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// renderServerDiff renders a colorized (when appropriate), pruned unified diff
+// between before and after, with Secret values redacted on both sides.
+// sinkSupportsColor is forwarded to DiffOptions.shouldColorize.
+func renderServerDiff(before, after *unstructured.Unstructured, opts DiffOptions, sinkSupportsColor bool) (string, error) {
+	beforeYAML, err := canonicalYAML(before, opts)
+	if err != nil {
+		return "", err
+	}
+
+	afterYAML, err := canonicalYAML(after, opts)
+	if err != nil {
+		return "", err
+	}
+
+	contextLines := opts.ContextLines
+	if contextLines <= 0 {
+		contextLines = defaultDiffContextLines
+	}
+
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(beforeYAML),
+		B:        difflib.SplitLines(afterYAML),
+		FromFile: "live",
+		ToFile:   "server-dry-run",
+		Context:  contextLines,
+	}
+
+	out, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return "", fmt.Errorf("failed to render diff: %w", err)
+	}
+
+	if opts.shouldColorize(sinkSupportsColor) {
+		out = colorizeDiff(out)
+	}
+
+	return out, nil
+}
+
+func canonicalYAML(obj *unstructured.Unstructured, opts DiffOptions) (string, error) {
+	pruned := obj.DeepCopy()
+
+	if pruned.GetKind() == "Secret" {
+		redactSecretData(pruned)
+	}
+
+	for _, fields := range alwaysPrunedFields {
+		unstructured.RemoveNestedField(pruned.Object, fields...)
+	}
+
+	if !opts.IncludeStatus {
+		unstructured.RemoveNestedField(pruned.Object, "status")
+	}
+
+	for _, path := range opts.PruneFields {
+		unstructured.RemoveNestedField(pruned.Object, strings.Split(path, ".")...)
+	}
+
+	return redactedYAML(pruned)
+}
+
+const (
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// colorizeDiff wraps unified-diff "+" lines in green and "-" lines in red,
+// leaving file headers ("+++"/"---") and context lines untouched.
+func colorizeDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			lines[i] = ansiGreen + line + ansiReset
+		case strings.HasPrefix(line, "-"):
+			lines[i] = ansiRed + line + ansiReset
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
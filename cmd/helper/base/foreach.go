@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+// defaultConcurrency is the worker pool size ForEachCluster falls back to when
+// no parallelism is set and there are no clusters, so the pool is never empty.
+const defaultConcurrency = 1
+
+// maxDefaultParallelism bounds the parallelism ForEachCluster picks on its own
+// (min(maxDefaultParallelism, len(clusters))) when neither WithParallelism nor
+// ToolConfig.Concurrency is set.
+const maxDefaultParallelism = 8
+
+// forEachConfig holds the options a ForEachOption mutates.
+type forEachConfig struct {
+	parallelism       int
+	failFast          bool
+	perClusterTimeout time.Duration
+}
+
+// ForEachOption configures a single ForEachCluster call.
+type ForEachOption func(*forEachConfig)
+
+// WithParallelism bounds the number of clusters ForEachCluster works on at
+// once. A non-positive n is ignored (the default is used instead).
+func WithParallelism(n int) ForEachOption {
+	return func(c *forEachConfig) {
+		if n > 0 {
+			c.parallelism = n
+		}
+	}
+}
+
+// WithFailFast cancels every in-flight cluster's context as soon as any
+// cluster's fn returns an error; clusters not yet started at that point are
+// skipped, so fn is never called for them and no error is recorded for them.
+// When false (the default), every cluster still gets a chance to run and
+// errors are collected via AddErrorFor.
+func WithFailFast(failFast bool) ForEachOption {
+	return func(c *forEachConfig) {
+		c.failFast = failFast
+	}
+}
+
+// WithPerClusterTimeout bounds how long fn may run for a single cluster. A
+// non-positive d (the default) means no per-cluster timeout is applied.
+func WithPerClusterTimeout(d time.Duration) ForEachOption {
+	return func(c *forEachConfig) {
+		c.perClusterTimeout = d
+	}
+}
+
+// ForEachCluster runs fn once per cluster returned by GetClusters, bounded by a
+// worker pool sized by WithParallelism (falling back to ToolConfig.Concurrency,
+// then min(maxDefaultParallelism, len(clusters)), then defaultConcurrency).
+// Errors returned by fn are recorded per-cluster via AddErrorFor rather than
+// aborting the whole run, so existing HasError/GetErrorFor callers keep working
+// unchanged. Each goroutine gets its own context, derived from ctx and
+// optionally bounded by WithPerClusterTimeout; with WithFailFast(true), that
+// context (and every other in-flight cluster's) is cancelled as soon as any
+// cluster's fn returns an error, and clusters not yet started are skipped.
+func (t *Tool) ForEachCluster(
+	ctx context.Context,
+	fn func(context.Context, *clusterv1.Cluster) error,
+	opts ...ForEachOption,
+) error {
+	clusters, err := t.GetClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg := &forEachConfig{parallelism: t.config.Concurrency} //nolint:exhaustivestruct
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.parallelism <= 0 {
+		cfg.parallelism = maxDefaultParallelism
+		if len(clusters) < cfg.parallelism {
+			cfg.parallelism = len(clusters)
+		}
+	}
+
+	if cfg.parallelism <= 0 {
+		cfg.parallelism = defaultConcurrency
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, cfg.parallelism)
+
+	var wg sync.WaitGroup
+
+	for _, cluster := range clusters {
+		cluster := cluster
+
+		acquired := false
+
+		select {
+		case <-runCtx.Done():
+		case sem <- struct{}{}:
+			acquired = true
+		}
+
+		if !acquired {
+			break
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			clusterCtx := runCtx
+
+			if cfg.perClusterTimeout > 0 {
+				var clusterCancel context.CancelFunc
+
+				clusterCtx, clusterCancel = context.WithTimeout(runCtx, cfg.perClusterTimeout)
+				defer clusterCancel()
+			}
+
+			if err := fn(clusterCtx, cluster); err != nil {
+				t.AddErrorFor(cluster, err)
+
+				if cfg.failFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
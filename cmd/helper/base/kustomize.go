@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// kustomizeApplyOrder buckets well-known kinds so that a kustomize bundle is
+// installed in a sane dependency order (namespaces and CRDs before the objects
+// that rely on them), falling back to the order kustomize itself produced for
+// anything not listed.
+var kustomizeApplyOrder = map[string]int{
+	"Namespace":               0,
+	"CustomResourceDefinition": 1,
+	"ClusterRole":             2,
+	"ClusterRoleBinding":      2,
+	"Role":                    2,
+	"RoleBinding":             2,
+	"ServiceAccount":          2,
+}
+
+const kustomizeApplyOrderDefault = 3
+
+// kustomizeApplyRank returns kind's position in kustomizeApplyOrder, falling
+// back to kustomizeApplyOrderDefault (after every bucketed kind) for anything
+// not listed there, e.g. Deployment, Service, ConfigMap, Secret, Job.
+func kustomizeApplyRank(kind string) int {
+	if rank, ok := kustomizeApplyOrder[kind]; ok {
+		return rank
+	}
+
+	return kustomizeApplyOrderDefault
+}
+
+// WorkloadApplyKustomization builds the kustomize overlay at path within fsys and
+// applies every resulting object to the workload cluster in dependency order
+// (Namespaces → CRDs → RBAC → everything else), reusing the dry-run, diff and
+// secret-redaction behavior of WorkloadPatchOrCreateUnstructured for each object.
+// This lets migration helpers ship per-cluster fix-ups as versioned kustomize
+// bundles instead of hand-built unstructured.Unstructured literals.
+func (t *Tool) WorkloadApplyKustomization(ctx context.Context, c *clusterv1.Cluster, fsys fs.FS, path string) error {
+	objects, err := buildKustomization(fsys, path)
+	if err != nil {
+		return fmt.Errorf("failed to build kustomization at %s: %w", path, err)
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return kustomizeApplyRank(objects[i].GetKind()) < kustomizeApplyRank(objects[j].GetKind())
+	})
+
+	for _, obj := range objects {
+		if err := t.WorkloadPatchOrCreateUnstructured(ctx, c, obj); err != nil {
+			return fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), ObjectToName(obj), err)
+		}
+	}
+
+	return nil
+}
+
+func buildKustomization(fsys fs.FS, path string) ([]*unstructured.Unstructured, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	resMap, err := kustomizer.Run(&fsAdapter{fsys: fsys}, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run kustomize: %w", err)
+	}
+
+	objects := make([]*unstructured.Unstructured, 0, resMap.Size())
+
+	for _, res := range resMap.Resources() {
+		content, err := res.Map()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kustomize resource: %w", err)
+		}
+
+		objects = append(objects, &unstructured.Unstructured{Object: content})
+	}
+
+	return objects, nil
+}
+
+// fsAdapter exposes a read-only io/fs.FS as the filesys.FileSystem kustomize's
+// loader expects, so kustomize bundles can be shipped as embed.FS or tested
+// against an in-memory fstest.MapFS without touching real disk.
+type fsAdapter struct {
+	fsys fs.FS
+}
+
+func clean(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+func (a *fsAdapter) Create(string) (filesys.File, error) {
+	return nil, fmt.Errorf("fsAdapter is read-only: create not supported")
+}
+
+func (a *fsAdapter) MkdirAll(string) error {
+	return fmt.Errorf("fsAdapter is read-only: mkdir not supported")
+}
+
+func (a *fsAdapter) RemoveAll(string) error {
+	return fmt.Errorf("fsAdapter is read-only: remove not supported")
+}
+
+func (a *fsAdapter) Open(path string) (filesys.File, error) {
+	f, err := a.fsys.Open(clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	ro, ok := f.(interface {
+		fs.File
+	})
+	if !ok {
+		return nil, fmt.Errorf("file %s does not support required operations", path)
+	}
+
+	return &readOnlyFile{File: ro}, nil
+}
+
+func (a *fsAdapter) Exists(path string) bool {
+	_, err := fs.Stat(a.fsys, clean(path))
+
+	return err == nil
+}
+
+func (a *fsAdapter) IsDir(path string) bool {
+	info, err := fs.Stat(a.fsys, clean(path))
+
+	return err == nil && info.IsDir()
+}
+
+func (a *fsAdapter) ReadDir(path string) ([]string, error) {
+	entries, err := fs.ReadDir(a.fsys, clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	return names, nil
+}
+
+func (a *fsAdapter) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	cleaned := clean(path)
+	if a.IsDir(cleaned) {
+		return filesys.ConfirmedDir(cleaned), "", nil
+	}
+
+	return filesys.ConfirmedDir(filepath.Dir(cleaned)), filepath.Base(cleaned), nil
+}
+
+func (a *fsAdapter) Glob(pattern string) ([]string, error) {
+	return fs.Glob(a.fsys, clean(pattern))
+}
+
+func (a *fsAdapter) ReadFile(path string) ([]byte, error) {
+	return fs.ReadFile(a.fsys, clean(path))
+}
+
+func (a *fsAdapter) WriteFile(string, []byte) error {
+	return fmt.Errorf("fsAdapter is read-only: write not supported")
+}
+
+func (a *fsAdapter) Walk(path string, walkFn filepath.WalkFunc) error {
+	return fs.WalkDir(a.fsys, clean(path), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return walkFn(p, nil, err)
+		}
+
+		info, infoErr := d.Info()
+
+		return walkFn(p, info, infoErr)
+	})
+}
+
+// readOnlyFile adapts an fs.File to filesys.File, which additionally requires
+// Write. Writes are rejected since kustomize only ever reads bundle sources.
+type readOnlyFile struct {
+	fs.File
+}
+
+func (f *readOnlyFile) Write(_ []byte) (int, error) {
+	return 0, fmt.Errorf("readOnlyFile: write not supported")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const redactedValue = "<redacted>"
+
+// secretDataFields are the unstructured paths on a core/v1 Secret whose values must
+// never be rendered verbatim.
+var secretDataFields = [][]string{{"data"}, {"stringData"}}
+
+// redactedYAML marshals obj to YAML for display purposes, replacing the values of
+// any Secret data/stringData fields so sensitive content is never written into
+// per-cluster output buffers, logs, or terminals.
+func redactedYAML(obj runtime.Object) (string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert object for rendering: %w", err)
+	}
+
+	u := unstructured.Unstructured{Object: content}
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		u.SetGroupVersionKind(gvk)
+	}
+
+	if u.GetKind() == "Secret" {
+		redactSecretData(&u)
+	}
+
+	out, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal object to yaml: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// redactSecretData replaces every value under a Secret's data/stringData maps with
+// redactedValue, in place.
+func redactSecretData(u *unstructured.Unstructured) {
+	for _, fields := range secretDataFields {
+		data, found, err := unstructured.NestedMap(u.Object, fields...)
+		if err != nil || !found {
+			continue
+		}
+
+		for key := range data {
+			data[key] = redactedValue
+		}
+
+		_ = unstructured.SetNestedMap(u.Object, data, fields...)
+	}
+}
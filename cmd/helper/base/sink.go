@@ -0,0 +1,264 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+// Op identifies the kind of operation an OutputEvent describes.
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpPatch  Op = "patch"
+	OpApply  Op = "apply"
+	OpDelete Op = "delete"
+	OpNoop   Op = "noop"
+	OpError  Op = "error"
+)
+
+// OutputEvent describes a single change (or attempted change) to a single object
+// on a single workload cluster, emitted by the Workload* helpers.
+type OutputEvent struct {
+	Op        Op
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+	Diff      string
+	DryRun    bool
+	Err       error
+}
+
+// OutputSink receives one OutputEvent per object reconciled against cluster. The
+// Workload* write helpers and AddErrorFor route their reporting through a Tool's
+// configured OutputSink (ToolConfig.OutputSink), defaulting to an in-memory
+// buffer per cluster so existing GetOutputFor/GetBufferFor callers keep working
+// unchanged. WorkloadWait and WorkloadWaitAll do not use the sink: their
+// progress lines are always written to the per-cluster buffer (GetBufferFor).
+type OutputSink interface {
+	Emit(cluster *clusterv1.Cluster, event OutputEvent) error
+}
+
+// emit renders event through the Tool's configured OutputSink, falling back to the
+// default in-memory buffer sink.
+func (t *Tool) emit(c *clusterv1.Cluster, event OutputEvent) {
+	sink := t.outputSink()
+	if err := sink.Emit(c, event); err != nil {
+		// Output sinks are best-effort reporting; a failure to record an event
+		// must never mask the underlying reconcile error.
+		fmt.Fprintf(t.GetBufferFor(c), "failed to emit output event: %v\n", err) //nolint:errcheck
+	}
+}
+
+// colorableSink is implemented by OutputSinks whose destination may be a
+// terminal a human is watching, so ANSI-colored diffs make sense there.
+// JSONLSink and FileSink deliberately do not implement it: they write
+// structured data for machine consumption, and embedding ANSI escapes in a
+// "diff" field would defeat that.
+type colorableSink interface {
+	supportsColor() bool
+}
+
+// sinkSupportsColor reports whether the Tool's configured OutputSink wants
+// diffs colorized, used by renderDryRunDiff instead of checking os.Stdout
+// directly so the decision tracks the sink actually in use.
+func (t *Tool) sinkSupportsColor() bool {
+	cs, ok := t.outputSink().(colorableSink)
+	if !ok {
+		return false
+	}
+
+	return cs.supportsColor()
+}
+
+func (t *Tool) outputSink() OutputSink {
+	// t.config is set once by Configure before any concurrent use begins, so
+	// reading OutputSink here needs no lock (consistent with other read-only
+	// config field access, e.g. ForEachCluster's use of config.Concurrency).
+	if t.config.OutputSink != nil {
+		return t.config.OutputSink
+	}
+
+	t.sinkMu.Lock()
+	defer t.sinkMu.Unlock()
+
+	if t.defaultSink == nil {
+		t.defaultSink = &bufferSink{tool: t}
+	}
+
+	return t.defaultSink
+}
+
+// bufferSink is the default OutputSink: it renders events as the same
+// human-readable lines the helpers used to fmt.Fprintf directly, into the
+// per-cluster buffer exposed by GetBufferFor/GetOutputFor.
+type bufferSink struct {
+	tool *Tool
+}
+
+// opVerb/opPastTense render an Op in the present and past tense used by the
+// human-readable messages below (e.g. "create"/"created", "apply"/"applied").
+var (
+	opVerb = map[Op]string{
+		OpCreate: "create",
+		OpPatch:  "patch",
+		OpApply:  "apply",
+		OpDelete: "delete",
+	}
+	opPastTense = map[Op]string{
+		OpCreate: "created",
+		OpPatch:  "patched",
+		OpApply:  "applied",
+		OpDelete: "deleted",
+	}
+)
+
+// supportsColor reports whether stdout is a terminal, since bufferSink's
+// contents (via GetOutputFor/GetBufferFor) are the human-readable output
+// callers conventionally print there.
+func (b *bufferSink) supportsColor() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+func (b *bufferSink) Emit(c *clusterv1.Cluster, event OutputEvent) error {
+	buf := b.tool.GetBufferFor(c)
+
+	name := event.Name
+	if event.Namespace != "" {
+		name = fmt.Sprintf("%s/%s", event.Namespace, event.Name)
+	}
+
+	switch {
+	case event.Op == OpError:
+		fmt.Fprintf(buf, "❌ %s %s: %v\n", event.GVK.Kind, name, event.Err)
+	case event.Op == OpNoop:
+		fmt.Fprintf(buf, "⏸️  %s %s is already up to date\n", event.GVK.Kind, name)
+	case event.DryRun && event.Diff != "":
+		fmt.Fprintf(buf, "(Dry Run) Would %s %s %s:\n%s\n", opVerb[event.Op], event.GVK.Kind, name, event.Diff)
+	case event.DryRun:
+		fmt.Fprintf(buf, "(Dry Run) Would %s %s %s\n", opVerb[event.Op], event.GVK.Kind, name)
+	case event.Diff != "":
+		fmt.Fprintf(buf, "✅ %s %s has been successfully %s:\n%s\n", event.GVK.Kind, name, opPastTense[event.Op], event.Diff)
+	default:
+		fmt.Fprintf(buf, "✅ %s %s has been successfully %s\n", event.GVK.Kind, name, opPastTense[event.Op])
+	}
+
+	return nil
+}
+
+// JSONLSink writes one JSON object per OutputEvent, newline-delimited, to w. It is
+// safe for concurrent use.
+type JSONLSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLSink returns a JSONLSink that writes events to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w} //nolint:exhaustivestruct
+}
+
+type jsonlEvent struct {
+	Cluster   string `json:"cluster"`
+	Op        Op     `json:"op"`
+	GVK       string `json:"gvk"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Diff      string `json:"diff,omitempty"`
+	DryRun    bool   `json:"dryRun,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (s *JSONLSink) Emit(cluster *clusterv1.Cluster, event OutputEvent) error {
+	line := jsonlEvent{ //nolint:exhaustivestruct
+		Cluster:   ObjectToName(cluster),
+		Op:        event.Op,
+		GVK:       event.GVK.String(),
+		Namespace: event.Namespace,
+		Name:      event.Name,
+		Diff:      event.Diff,
+		DryRun:    event.DryRun,
+	}
+	if event.Err != nil {
+		line.Error = event.Err.Error()
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintln(s.w, string(encoded)); err != nil {
+		return fmt.Errorf("failed to write output event: %w", err)
+	}
+
+	return nil
+}
+
+// FileSink writes one JSONL file per cluster under Dir, named
+// "<cluster-namespace>_<cluster-name>.jsonl".
+type FileSink struct {
+	Dir string
+
+	mu    sync.Mutex
+	files map[string]*JSONLSink
+}
+
+func (s *FileSink) Emit(cluster *clusterv1.Cluster, event OutputEvent) error {
+	sink, err := s.sinkFor(cluster)
+	if err != nil {
+		return err
+	}
+
+	return sink.Emit(cluster, event)
+}
+
+func (s *FileSink) sinkFor(cluster *clusterv1.Cluster) (*JSONLSink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.files == nil {
+		s.files = make(map[string]*JSONLSink)
+	}
+
+	key := ObjectToName(cluster)
+	if sink, ok := s.files[key]; ok {
+		return sink, nil
+	}
+
+	fileName := fmt.Sprintf("%s_%s.jsonl", cluster.GetNamespace(), cluster.GetName())
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, fileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file for cluster %s: %w", key, err)
+	}
+
+	sink := NewJSONLSink(f)
+	s.files[key] = sink
+
+	return sink, nil
+}
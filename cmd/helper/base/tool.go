@@ -17,11 +17,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"reflect"
 	"sync"
+	"time"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -32,6 +37,24 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// ApplyMode selects how Workload* write helpers reconcile the desired state of an
+// object with a workload cluster.
+type ApplyMode string
+
+const (
+	// ApplyModeClientSide is the default mode: a get-then-diff-then-patch/create
+	// sequence where this helper decides the final state of the object.
+	ApplyModeClientSide ApplyMode = "ClientSide"
+
+	// ApplyModeServerSide uses Kubernetes server-side apply so this helper only
+	// owns the fields it sets, coexisting with edits made by other field managers.
+	ApplyModeServerSide ApplyMode = "ServerSide"
+
+	// defaultFieldManager is used for server-side apply when the caller does not
+	// provide one.
+	defaultFieldManager = "capi-packet-helper"
+)
+
 func ObjectToName(obj controllerutil.Object) string {
 	if obj.GetNamespace() != "" {
 		return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
@@ -48,18 +71,81 @@ type ToolConfig struct {
 	WatchingNamespace    string
 	WorkloadClientGetter remote.ClusterClientGetter
 	DryRun               bool
+	ApplyMode            ApplyMode
+	Concurrency          int
+	DiffFormat           DiffFormat
+	OutputSink           OutputSink
+	CacheResyncPeriod    time.Duration
+	DisableCache         bool
+	WarmGVKs             []schema.GroupVersionKind
+	DiffOptions          DiffOptions
 }
 
 type Tool struct {
-	mgmtClient      client.Client
-	scheme          *runtime.Scheme
-	config          *ToolConfig
-	baseMutex       sync.Mutex
-	clusters        []*clusterv1.Cluster
-	workloadClients map[string]client.Client
-	errors          map[string]error
-	outputBuffers   map[string]*bytes.Buffer
-	outputContents  map[string]string
+	mgmtClient client.Client
+	scheme     *runtime.Scheme
+	config     *ToolConfig
+	baseMutex  sync.Mutex
+	clusters   []*clusterv1.Cluster
+	mgmtCache  *cachedClient
+
+	// sinkMu guards defaultSink's lazy initialization. It is separate from
+	// baseMutex so that emit(), called on every Workload* operation for every
+	// cluster, never blocks behind a slow ManagementClient construction held by
+	// baseMutex.
+	sinkMu      sync.Mutex
+	defaultSink OutputSink
+
+	// errorsMu guards errors, populated by AddErrorFor and read by
+	// GetErrorFor/HasError. It is separate from baseMutex so that a cluster's
+	// error doesn't contend with unrelated buffer or client-map access.
+	errorsMu sync.Mutex
+	errors   map[string]error
+
+	// outputBuffersMu guards only the outputBuffers map itself (lookups and
+	// inserts); each clusterBuffer locks independently on Write, so writes for
+	// different clusters never block each other.
+	outputBuffersMu sync.Mutex
+	outputBuffers   map[string]*clusterBuffer
+
+	// outputContentsMu guards outputContents, the flushed (string) contents
+	// returned by GetOutputFor.
+	outputContentsMu sync.Mutex
+	outputContents   map[string]string
+
+	// workloadClientsMu guards workloadClients and workloadCaches.
+	// workloadClientOnces ensures a given cluster's client is constructed (a
+	// potentially slow remote call) exactly once, without holding
+	// workloadClientsMu for the duration of that construction.
+	workloadClientsMu   sync.Mutex
+	workloadClients     map[string]client.Client
+	workloadCaches      map[string]*cachedClient
+	workloadClientOnces sync.Map
+}
+
+// clusterBuffer is a per-cluster output buffer with its own mutex, so that a
+// write for one cluster never blocks a write or drain for another.
+type clusterBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *clusterBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// drain returns everything written to b so far and resets it.
+func (b *clusterBuffer) drain() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := b.buf.String()
+	b.buf.Reset()
+
+	return out
 }
 
 func (t *Tool) WatchingNamespace() string {
@@ -90,6 +176,19 @@ func (t *Tool) WorkloadPatch(
 		return err
 	}
 
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to create object key: %w", err)
+	}
+
+	var before *unstructured.Unstructured
+	if t.DryRun() {
+		before, err = fetchForDiff(ctx, workloadClient, key, obj)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := workloadClient.Patch(ctx, obj, patch, opts...); err != nil {
 		return err
 	}
@@ -99,19 +198,26 @@ func (t *Tool) WorkloadPatch(
 		return err
 	}
 
+	diff := ""
 	if t.DryRun() {
-		// TODO: show diff
-		fmt.Fprintf(t.GetBufferFor(c), "(Dry Run) Would patch %s %s\n", gvk.Kind, ObjectToName(obj))
-
-		return nil
+		if diff, err = t.renderDryRunDiff(before, obj); err != nil {
+			return err
+		}
 	}
 
-	fmt.Fprintf(t.GetBufferFor(c), "✅ %s %s has been successfully patched\n", gvk.Kind, ObjectToName(obj))
+	t.emit(c, OutputEvent{Op: OpPatch, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName(), Diff: diff, DryRun: t.DryRun()})
 
 	return nil
 }
 
+// WorkloadCreate creates obj on the workload cluster. If ToolConfig.ApplyMode is
+// ApplyModeServerSide, the create is instead delegated to WorkloadApply so the
+// object is reconciled with server-side apply throughout its lifecycle.
 func (t *Tool) WorkloadCreate(ctx context.Context, c *clusterv1.Cluster, obj controllerutil.Object) error {
+	if t.config.ApplyMode == ApplyModeServerSide {
+		return t.WorkloadApply(ctx, c, obj, "")
+	}
+
 	var opts []client.CreateOption
 	if t.DryRun() {
 		opts = append(opts, client.DryRunAll)
@@ -131,13 +237,14 @@ func (t *Tool) WorkloadCreate(ctx context.Context, c *clusterv1.Cluster, obj con
 		return err
 	}
 
+	diff := ""
 	if t.DryRun() {
-		fmt.Fprintf(t.GetBufferFor(c), "(Dry Run) Would create %s %s\n", gvk.Kind, ObjectToName(obj))
-
-		return nil
+		if diff, err = t.renderDryRunDiff(new(unstructured.Unstructured), obj); err != nil {
+			return err
+		}
 	}
 
-	fmt.Fprintf(t.GetBufferFor(c), "✅ %s %s has been successfully created\n", gvk.Kind, ObjectToName(obj))
+	t.emit(c, OutputEvent{Op: OpCreate, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName(), Diff: diff, DryRun: t.DryRun()})
 
 	return nil
 }
@@ -153,6 +260,19 @@ func (t *Tool) WorkloadDelete(ctx context.Context, c *clusterv1.Cluster, obj con
 		return err
 	}
 
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to create object key: %w", err)
+	}
+
+	var before *unstructured.Unstructured
+	if t.DryRun() {
+		before, err = fetchForDiff(ctx, workloadClient, key, obj)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := workloadClient.Delete(ctx, obj, opts...); err != nil {
 		return err
 	}
@@ -162,13 +282,160 @@ func (t *Tool) WorkloadDelete(ctx context.Context, c *clusterv1.Cluster, obj con
 		return err
 	}
 
+	diff := ""
 	if t.DryRun() {
-		fmt.Fprintf(t.GetBufferFor(c), "(Dry Run) Would delete %s %s\n", gvk.Kind, ObjectToName(obj))
+		if diff, err = t.renderDryRunDiff(before, new(unstructured.Unstructured)); err != nil {
+			return err
+		}
+	}
+
+	t.emit(c, OutputEvent{Op: OpDelete, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName(), Diff: diff, DryRun: t.DryRun()})
+
+	return nil
+}
+
+// renderDryRunDiff converts after (the server's dry-run projected object, as
+// populated in place by a Patch/Create/Delete call made with client.DryRunAll)
+// to unstructured and renders a pruned, redacted unified diff against before.
+func (t *Tool) renderDryRunDiff(before *unstructured.Unstructured, after runtime.Object) (string, error) {
+	afterUnstructured, err := toUnstructured(after)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := renderServerDiff(before, afterUnstructured, t.config.DiffOptions, t.sinkSupportsColor())
+	if err != nil {
+		return "", fmt.Errorf("failed to render dry-run diff: %w", err)
+	}
+
+	return diff, nil
+}
+
+// WorkloadApply reconciles obj on the workload cluster using server-side apply (SSA)
+// instead of the get-then-diff-then-patch/create sequence used by WorkloadCreate and
+// WorkloadPatch. fieldManager identifies the set of fields this call owns; if empty,
+// defaultFieldManager is used. Under DryRun() the request is sent with
+// metav1.DryRunAll and the server-produced object (including server-defaulted fields)
+// is rendered into the per-cluster output buffer, with secret values redacted.
+func (t *Tool) WorkloadApply(
+	ctx context.Context,
+	c *clusterv1.Cluster,
+	obj controllerutil.Object,
+	fieldManager string,
+) error {
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	opts := []client.PatchOption{client.ForceOwnership, client.FieldOwner(fieldManager)}
+	if t.DryRun() {
+		opts = append(opts, client.DryRunAll)
+	}
+
+	workloadClient, err := t.getWorkloadClient(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if err := workloadClient.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		return fmt.Errorf("failed to apply object: %w", err)
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, t.scheme)
+	if err != nil {
+		return err
+	}
+
+	diff := ""
+	if t.DryRun() {
+		rendered, err := redactedYAML(obj)
+		if err != nil {
+			return fmt.Errorf("failed to render dry-run result: %w", err)
+		}
+
+		diff = rendered
+	}
+
+	t.emit(c, OutputEvent{
+		Op: OpApply, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName(), Diff: diff, DryRun: t.DryRun(),
+	})
+
+	return nil
+}
+
+// WorkloadPatchOrCreateUnstructured reconciles obj on the workload cluster: if no
+// object with obj's namespace/name exists it is created via WorkloadCreate;
+// otherwise obj's fields are merged onto the live object and, if that changes
+// anything, the merged object is patched. When it detects a change, a structured
+// diff (ToolConfig.DiffFormat, default DiffFormatYAML) is written to the
+// per-cluster output buffer so operators can see exactly what would change,
+// with Secret values redacted in every format. Under DryRun() the patch is sent
+// with client.DryRunAll and nothing is persisted.
+//
+// If ToolConfig.ApplyMode is ApplyModeServerSide, the get-then-diff-then-patch
+// sequence below is skipped entirely in favor of a single WorkloadApply call,
+// since server-side apply already handles the create-or-update decision itself.
+func (t *Tool) WorkloadPatchOrCreateUnstructured(
+	ctx context.Context,
+	c *clusterv1.Cluster,
+	obj *unstructured.Unstructured,
+) error {
+	if t.config.ApplyMode == ApplyModeServerSide {
+		return t.WorkloadApply(ctx, c, obj, "")
+	}
+
+	workloadClient, err := t.getWorkloadClient(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to create object key: %w", err)
+	}
+
+	existing := new(unstructured.Unstructured)
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+
+	if err := workloadClient.Get(ctx, key, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		return t.WorkloadCreate(ctx, c, obj)
+	}
+
+	desired := existing.DeepCopy()
+	mergeUnstructured(desired.UnstructuredContent(), obj.UnstructuredContent())
+
+	gvk, err := apiutil.GVKForObject(obj, t.scheme)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(existing.UnstructuredContent(), desired.UnstructuredContent()) {
+		t.emit(c, OutputEvent{Op: OpNoop, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName()})
 
 		return nil
 	}
 
-	fmt.Fprintf(t.GetBufferFor(c), "✅ %s %s has been successfully deleted\n", gvk.Kind, ObjectToName(obj))
+	diff, err := renderDiff(t.config.DiffFormat, existing, desired)
+	if err != nil {
+		return fmt.Errorf("failed to render diff: %w", err)
+	}
+
+	var opts []client.PatchOption
+	if t.DryRun() {
+		opts = append(opts, client.DryRunAll)
+	}
+
+	if err := workloadClient.Patch(ctx, desired, client.MergeFrom(existing), opts...); err != nil {
+		return fmt.Errorf("failed to patch object: %w", err)
+	}
+
+	t.emit(c, OutputEvent{
+		Op: OpPatch, GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName(), Diff: diff, DryRun: t.DryRun(),
+	})
 
 	return nil
 }
@@ -272,14 +539,52 @@ func (t *Tool) ManagementClient() (client.Client, error) {
 		t.config.RestConfig = config
 	}
 
-	c, err := client.New(t.config.RestConfig, client.Options{Scheme: t.scheme}) //nolint:exhaustivestruct
+	if t.config.DisableCache {
+		c, err := client.New(t.config.RestConfig, client.Options{Scheme: t.scheme}) //nolint:exhaustivestruct
+		if err != nil {
+			return nil, fmt.Errorf("failed to create managmement cluster client: %w", err)
+		}
+
+		t.mgmtClient = c
+
+		return c, nil
+	}
+
+	cached, err := newCachedClient(
+		context.Background(), t.config.RestConfig, t.scheme, t.config.CacheResyncPeriod, t.config.WarmGVKs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create managmement cluster client: %w", err)
+		return nil, fmt.Errorf("failed to create cached managmement cluster client: %w", err)
 	}
 
-	t.mgmtClient = c
+	t.mgmtCache = cached
+	t.mgmtClient = cached
 
-	return c, nil
+	return cached, nil
+}
+
+// Close stops every cache started by ManagementClient and getWorkloadClient and
+// clears the per-cluster client map. It is safe, but unnecessary, to call more
+// than once. Tool is not usable after Close returns.
+func (t *Tool) Close() {
+	t.baseMutex.Lock()
+
+	if t.mgmtCache != nil {
+		t.mgmtCache.Stop()
+		t.mgmtCache = nil
+	}
+
+	t.baseMutex.Unlock()
+
+	t.workloadClientsMu.Lock()
+	defer t.workloadClientsMu.Unlock()
+
+	for key, cached := range t.workloadCaches {
+		cached.Stop()
+		delete(t.workloadCaches, key)
+	}
+
+	t.workloadClients = nil
+	t.workloadClientOnces = sync.Map{}
 }
 
 func (t *Tool) Configure(toolConfig *ToolConfig) {
@@ -294,8 +599,8 @@ func (t *Tool) HasError(c *clusterv1.Cluster) bool {
 }
 
 func (t *Tool) GetErrorFor(c *clusterv1.Cluster) error {
-	t.baseMutex.Lock()
-	defer t.baseMutex.Unlock()
+	t.errorsMu.Lock()
+	defer t.errorsMu.Unlock()
 
 	if t.errors == nil {
 		return nil
@@ -305,11 +610,11 @@ func (t *Tool) GetErrorFor(c *clusterv1.Cluster) error {
 }
 
 func (t *Tool) GetOutputFor(c *clusterv1.Cluster) string {
-	t.baseMutex.Lock()
-	defer t.baseMutex.Unlock()
-
 	t.flushBuffers()
 
+	t.outputContentsMu.Lock()
+	defer t.outputContentsMu.Unlock()
+
 	if t.outputContents == nil {
 		return ""
 	}
@@ -318,84 +623,158 @@ func (t *Tool) GetOutputFor(c *clusterv1.Cluster) string {
 }
 
 func (t *Tool) AddErrorFor(c *clusterv1.Cluster, err error) {
-	t.baseMutex.Lock()
-	defer t.baseMutex.Unlock()
+	t.errorsMu.Lock()
 
 	if t.errors == nil {
 		t.errors = make(map[string]error)
 	}
 
 	t.errors[ObjectToName(c)] = err
+
+	t.errorsMu.Unlock()
+
+	t.emit(c, OutputEvent{Op: OpError, Err: err})
 }
 
-func (t *Tool) GetBufferFor(c *clusterv1.Cluster) *bytes.Buffer {
-	t.baseMutex.Lock()
-	defer t.baseMutex.Unlock()
+// GetBufferFor returns the per-cluster output buffer as an io.Writer. Writes
+// to the buffers for two different clusters never contend with each other;
+// only the (brief) lookup-or-create of a cluster's buffer is shared.
+func (t *Tool) GetBufferFor(c *clusterv1.Cluster) io.Writer {
+	key := ObjectToName(c)
+
+	t.outputBuffersMu.Lock()
+	defer t.outputBuffersMu.Unlock()
 
 	if t.outputBuffers == nil {
-		t.outputBuffers = make(map[string]*bytes.Buffer)
+		t.outputBuffers = make(map[string]*clusterBuffer)
 	}
 
-	key := ObjectToName(c)
-
 	if t.outputBuffers[key] == nil {
-		t.outputBuffers[key] = new(bytes.Buffer)
+		t.outputBuffers[key] = new(clusterBuffer)
 	}
 
 	return t.outputBuffers[key]
 }
 
 func (t *Tool) flushBuffers() {
-	if t.outputBuffers == nil {
-		t.outputBuffers = make(map[string]*bytes.Buffer)
+	t.outputBuffersMu.Lock()
+	buffers := make(map[string]*clusterBuffer, len(t.outputBuffers))
+
+	for key, buf := range t.outputBuffers {
+		buffers[key] = buf
 	}
 
+	t.outputBuffersMu.Unlock()
+
+	t.outputContentsMu.Lock()
+	defer t.outputContentsMu.Unlock()
+
 	if t.outputContents == nil {
 		t.outputContents = make(map[string]string)
 	}
 
-	for key, buf := range t.outputBuffers {
-		out, err := ioutil.ReadAll(buf)
-		if err != nil {
-			continue
-		}
-
-		t.outputContents[key] += string(out)
+	for key, buf := range buffers {
+		t.outputContents[key] += buf.drain()
 	}
 }
 
+// getWorkloadClient returns (constructing if necessary) the client.Client for
+// cluster. Construction happens under a per-cluster sync.Once so that two
+// goroutines racing to reach the same cluster's client for the first time
+// don't both pay the cost of a remote client build, while goroutines working
+// on unrelated clusters never wait on each other.
 func (t *Tool) getWorkloadClient(ctx context.Context, cluster *clusterv1.Cluster) (client.Client, error) {
 	mgmtClient, err := t.ManagementClient()
 	if err != nil {
 		return nil, err
 	}
 
+	key := ObjectToName(cluster)
+
+	onceAny, _ := t.workloadClientOnces.LoadOrStore(key, new(sync.Once))
+	once, _ := onceAny.(*sync.Once)
+
+	var initErr error
+
+	once.Do(func() {
+		initErr = t.initWorkloadClient(ctx, mgmtClient, cluster, key)
+	})
+
+	if initErr != nil {
+		// Allow a later call to retry construction instead of caching the failure.
+		t.workloadClientOnces.Delete(key)
+
+		return nil, initErr
+	}
+
+	t.workloadClientsMu.Lock()
+	defer t.workloadClientsMu.Unlock()
+
+	return t.workloadClients[key], nil
+}
+
+// initWorkloadClient builds the client.Client for the cluster identified by
+// key and stores it in t.workloadClients (and t.workloadCaches, if cached).
+// It is only ever invoked once per key, via the sync.Once in getWorkloadClient.
+func (t *Tool) initWorkloadClient(ctx context.Context, mgmtClient client.Client, cluster *clusterv1.Cluster, key string) error {
+	clusterKey, err := client.ObjectKeyFromObject(cluster)
+	if err != nil {
+		return fmt.Errorf("failed to create object key: %w", err)
+	}
+
 	t.baseMutex.Lock()
-	defer t.baseMutex.Unlock()
 
-	if t.workloadClients == nil {
-		t.workloadClients = make(map[string]client.Client)
+	if t.config.WorkloadClientGetter == nil {
+		t.config.WorkloadClientGetter = remote.NewClusterClient
 	}
 
-	key := ObjectToName(cluster)
+	workloadClientGetter := t.config.WorkloadClientGetter
 
-	if _, ok := t.workloadClients[key]; !ok {
-		clusterKey, err := client.ObjectKeyFromObject(cluster)
+	t.baseMutex.Unlock()
+
+	if t.config.DisableCache {
+		workloadClient, err := workloadClientGetter(ctx, mgmtClient, clusterKey, scheme.Scheme)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create object key: %w", err)
+			return fmt.Errorf("failed to create client: %w", err)
 		}
 
-		if t.config.WorkloadClientGetter == nil {
-			t.config.WorkloadClientGetter = remote.NewClusterClient
-		}
+		t.workloadClientsMu.Lock()
 
-		workloadClient, err := t.config.WorkloadClientGetter(ctx, mgmtClient, clusterKey, scheme.Scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create client: %w", err)
+		if t.workloadClients == nil {
+			t.workloadClients = make(map[string]client.Client)
 		}
 
 		t.workloadClients[key] = workloadClient
+
+		t.workloadClientsMu.Unlock()
+
+		return nil
 	}
 
-	return t.workloadClients[key], nil
+	restConfig, err := remote.RESTConfig(ctx, clusterKey.Name, mgmtClient, clusterKey)
+	if err != nil {
+		return fmt.Errorf("failed to get rest config for workload cluster %s: %w", key, err)
+	}
+
+	cached, err := newCachedClient(ctx, restConfig, t.scheme, t.config.CacheResyncPeriod, t.config.WarmGVKs)
+	if err != nil {
+		return fmt.Errorf("failed to create cached client for workload cluster %s: %w", key, err)
+	}
+
+	t.workloadClientsMu.Lock()
+
+	if t.workloadClients == nil {
+		t.workloadClients = make(map[string]client.Client)
+	}
+
+	if t.workloadCaches == nil {
+		t.workloadCaches = make(map[string]*cachedClient)
+	}
+
+	t.workloadCaches[key] = cached
+	t.workloadClients[key] = cached
+
+	t.workloadClientsMu.Unlock()
+
+	return nil
 }
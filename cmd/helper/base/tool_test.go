@@ -16,11 +16,14 @@ package base_test
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	. "github.com/onsi/gomega"
@@ -32,6 +35,7 @@ import (
 	"k8s.io/klog/v2/klogr"
 	"sigs.k8s.io/cluster-api-provider-packet/cmd/helper/base"
 	"sigs.k8s.io/cluster-api-provider-packet/cmd/helper/base/testutils"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -301,6 +305,567 @@ func TestTool_WorkloadPatchOrCreateUnstructuredDry(t *testing.T) {
 	g.Expect(actualDryRunMutate).NotTo(testutils.BeDerivativeOf(expectedResource))
 }
 
+func TestTool_WorkloadApplyServerSideDryRunRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithT(t)
+	ctx := context.TODO()
+	secret := &corev1.Secret{ // nolint:exhaustivestruct
+		ObjectMeta: metav1.ObjectMeta{ // nolint:exhaustivestruct
+			Namespace: fmt.Sprintf("test-%s", util.RandomString(6)),
+			Name:      fmt.Sprintf("test-secret-%s", util.RandomString(6)),
+		},
+		Data: map[string][]byte{
+			"color": []byte("yellow"),
+			"shape": []byte("square"),
+		},
+	}
+
+	cluster := testutils.GenerateCluster("", "")
+
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		DryRun:               true,
+		ApplyMode:            base.ApplyModeServerSide,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	g.Expect(tool.WorkloadApply(ctx, cluster, secret, "")).To(Succeed())
+
+	output := tool.GetOutputFor(cluster)
+	g.Expect(output).To(ContainSubstring("capi-packet-helper"))
+
+	for _, value := range secret.Data {
+		g.Expect(output).NotTo(ContainSubstring(string(value)))
+	}
+}
+
+func TestTool_WorkloadCreateServerSideApplyModeDispatchesToApply(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	secret := &corev1.Secret{ // nolint:exhaustivestruct
+		ObjectMeta: metav1.ObjectMeta{ // nolint:exhaustivestruct
+			Namespace: fmt.Sprintf("test-%s", util.RandomString(6)),
+			Name:      fmt.Sprintf("test-secret-%s", util.RandomString(6)),
+		},
+	}
+
+	cluster := testutils.GenerateCluster("", "")
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+
+	var out strings.Builder
+
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		ApplyMode:            base.ApplyModeServerSide,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+		OutputSink:           base.NewJSONLSink(&out),
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	// Setting ApplyMode alone, with no other change at the call site, must be
+	// enough to route WorkloadCreate through server-side apply.
+	g.Expect(tool.WorkloadCreate(ctx, cluster, secret)).To(Succeed())
+
+	var event map[string]interface{}
+	g.Expect(json.Unmarshal([]byte(strings.TrimSpace(out.String())), &event)).To(Succeed())
+	g.Expect(event["op"]).To(Equal("apply"))
+}
+
+func TestTool_WorkloadPatchDryRunRendersServerDiff(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithT(t)
+	ctx := context.TODO()
+	deployment := testutils.GenerateDeployment(
+		fmt.Sprintf("test-%s", util.RandomString(6)), fmt.Sprintf("test-deploy-%s", util.RandomString(6)), "test")
+
+	cluster := testutils.GenerateCluster("", "")
+	clusterKey, err := client.ObjectKeyFromObject(cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	workloadResources := map[client.ObjectKey][]runtime.Object{
+		clusterKey: {deployment},
+	}
+
+	testEnv := testutils.NewTestEnv(ctx, t, workloadResources, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		DryRun:               true,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Labels = map[string]string{"updated": "true"}
+
+	g.Expect(tool.WorkloadPatch(ctx, cluster, deployment, patch)).To(Succeed())
+
+	output := tool.GetOutputFor(cluster)
+	g.Expect(output).To(ContainSubstring("updated: \"true\""))
+	g.Expect(output).NotTo(ContainSubstring("managedFields"))
+	g.Expect(output).NotTo(ContainSubstring("resourceVersion"))
+}
+
+func TestTool_CloseIsSafeWithoutClients(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	tool := &base.Tool{}
+	tool.Configure(&base.ToolConfig{}) //nolint:exhaustivestruct
+
+	g.Expect(func() { tool.Close() }).NotTo(Panic())
+}
+
+func TestTool_WorkloadWaitDryRunSkipsPolling(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	deployment := testutils.GenerateDeployment(
+		fmt.Sprintf("test-%s", util.RandomString(6)), fmt.Sprintf("test-deploy-%s", util.RandomString(6)), "test")
+
+	cluster := testutils.GenerateCluster("", "")
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		DryRun:               true,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	g.Expect(tool.WorkloadWait(ctx, cluster, deployment, time.Second)).To(Succeed())
+	g.Expect(tool.GetOutputFor(cluster)).To(ContainSubstring("(Dry Run) Would wait for Deployment"))
+}
+
+func TestTool_WorkloadApplyBatchOrdersNamespaceBeforeDependents(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	namespaceName := fmt.Sprintf("test-%s", util.RandomString(6))
+	ns := testutils.GenerateNamespace(namespaceName)
+	deployment := testutils.GenerateDeployment(namespaceName, fmt.Sprintf("test-deploy-%s", util.RandomString(6)), "test")
+
+	cluster := testutils.GenerateCluster("", "")
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	// Intentionally out of dependency order: the batch must still install the
+	// namespace before the deployment that lives in it.
+	objects := []controllerutil.Object{deployment, ns}
+
+	g.Expect(tool.WorkloadApplyBatch(ctx, cluster, objects, "")).To(Succeed())
+
+	var actualNS corev1.Namespace
+	g.Expect(tool.WorkloadGet(ctx, cluster, client.ObjectKey{Name: namespaceName}, &actualNS)).To(Succeed())
+
+	var actualDeployment appsv1.Deployment
+	deploymentKey, err := client.ObjectKeyFromObject(deployment)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tool.WorkloadGet(ctx, cluster, deploymentKey, &actualDeployment)).To(Succeed())
+}
+
+func TestTool_WorkloadApplyBatchWaitsForUnstructuredCRD(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	crdName := fmt.Sprintf("widgets-%s.example.com", util.RandomString(6))
+	crd := &unstructured.Unstructured{}
+	crd.SetUnstructuredContent(map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": crdName,
+		},
+		"spec": map[string]interface{}{
+			"group": "example.com",
+			"names": map[string]interface{}{
+				"plural":   "widgets",
+				"singular": "widget",
+				"kind":     "Widget",
+				"listKind": "WidgetList",
+			},
+			"scope": "Namespaced",
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":    "v1",
+					"served":  true,
+					"storage": true,
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type":                                 "object",
+							"x-kubernetes-preserve-unknown-fields": true,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	cluster := testutils.GenerateCluster("", "")
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		DryRun:               true,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	// crd is a *unstructured.Unstructured, the shape WorkloadApplyKustomization
+	// produces, not a typed *apiextensionsv1.CustomResourceDefinition. The batch
+	// must still recognize it as a CRD and wait for it, not just order it first.
+	objects := []controllerutil.Object{crd}
+
+	g.Expect(tool.WorkloadApplyBatch(ctx, cluster, objects, "")).To(Succeed())
+	g.Expect(tool.GetOutputFor(cluster)).To(ContainSubstring("(Dry Run) Would wait for CustomResourceDefinition"))
+}
+
+func TestTool_WorkloadCreateJSONLSink(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	secret := &corev1.Secret{ // nolint:exhaustivestruct
+		ObjectMeta: metav1.ObjectMeta{ // nolint:exhaustivestruct
+			Namespace: fmt.Sprintf("test-%s", util.RandomString(6)),
+			Name:      fmt.Sprintf("test-secret-%s", util.RandomString(6)),
+		},
+	}
+
+	cluster := testutils.GenerateCluster("", "")
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+
+	var out strings.Builder
+
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+		OutputSink:           base.NewJSONLSink(&out),
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	g.Expect(tool.WorkloadCreate(ctx, cluster, secret)).To(Succeed())
+
+	var event map[string]interface{}
+	g.Expect(json.Unmarshal([]byte(strings.TrimSpace(out.String())), &event)).To(Succeed())
+	g.Expect(event["op"]).To(Equal("create"))
+	g.Expect(event["name"]).To(Equal(secret.Name))
+	g.Expect(event["namespace"]).To(Equal(secret.Namespace))
+}
+
+func TestTool_WorkloadPatchDryRunJSONLSinkDiffHasNoANSI(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	deployment := testutils.GenerateDeployment(
+		fmt.Sprintf("test-%s", util.RandomString(6)), fmt.Sprintf("test-deploy-%s", util.RandomString(6)), "test")
+
+	cluster := testutils.GenerateCluster("", "")
+	clusterKey, err := client.ObjectKeyFromObject(cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	workloadResources := map[client.ObjectKey][]runtime.Object{
+		clusterKey: {deployment},
+	}
+
+	testEnv := testutils.NewTestEnv(ctx, t, workloadResources, cluster)
+
+	var out strings.Builder
+
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		DryRun:               true,
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+		OutputSink:           base.NewJSONLSink(&out),
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Labels = map[string]string{"updated": "true"}
+
+	// Whether stdout happens to be a terminal in the process running this test
+	// must not matter: JSONLSink is a structured, machine-consumed sink, so its
+	// "diff" field must never contain ANSI escape codes.
+	g.Expect(tool.WorkloadPatch(ctx, cluster, deployment, patch)).To(Succeed())
+
+	var event map[string]interface{}
+	g.Expect(json.Unmarshal([]byte(strings.TrimSpace(out.String())), &event)).To(Succeed())
+	g.Expect(event["diff"]).To(ContainSubstring("updated: \"true\""))
+	g.Expect(event["diff"]).NotTo(ContainSubstring("\x1b["))
+}
+
+func TestTool_WorkloadApplyKustomization(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	namespaceName := fmt.Sprintf("test-%s", util.RandomString(6))
+	cluster := testutils.GenerateCluster("", "")
+
+	fsys := fstest.MapFS{
+		"kustomization.yaml": &fstest.MapFile{Data: []byte("resources:\n  - namespace.yaml\n")},
+		"namespace.yaml": &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", namespaceName))},
+	}
+
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	g.Expect(tool.WorkloadApplyKustomization(ctx, cluster, fsys, ".")).To(Succeed())
+
+	var ns corev1.Namespace
+	g.Expect(tool.WorkloadGet(ctx, cluster, client.ObjectKey{Name: namespaceName}, &ns)).To(Succeed())
+}
+
+func TestTool_WorkloadApplyKustomizationOrdersNamespaceBeforeUnbucketedKinds(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	namespaceName := fmt.Sprintf("test-%s", util.RandomString(6))
+	cluster := testutils.GenerateCluster("", "")
+
+	// ConfigMap has no entry in kustomizeApplyOrder, so it must still be applied
+	// after the Namespace it lives in; listing it first in resources catches a
+	// sort that leaves unbucketed kinds at the same rank as Namespace.
+	fsys := fstest.MapFS{
+		"kustomization.yaml": &fstest.MapFile{Data: []byte("resources:\n  - configmap.yaml\n  - namespace.yaml\n")},
+		"configmap.yaml": &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-config\n  namespace: %s\n", namespaceName))},
+		"namespace.yaml": &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", namespaceName))},
+	}
+
+	testEnv := testutils.NewTestEnv(ctx, t, nil, cluster)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		RestConfig:           testEnv.RestConfig,
+		WorkloadClientGetter: testEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	g.Expect(tool.WorkloadApplyKustomization(ctx, cluster, fsys, ".")).To(Succeed())
+
+	var cm corev1.ConfigMap
+	g.Expect(tool.WorkloadGet(
+		ctx, cluster, client.ObjectKey{Namespace: namespaceName, Name: "test-config"}, &cm)).To(Succeed())
+}
+
+func TestTool_WorkloadPatchOrCreateUnstructuredDiffFormats(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	secret := &corev1.Secret{ // nolint:exhaustivestruct
+		ObjectMeta: metav1.ObjectMeta{ // nolint:exhaustivestruct
+			Namespace: fmt.Sprintf("test-%s", util.RandomString(6)),
+			Name:      fmt.Sprintf("test-secret-%s", util.RandomString(6)),
+		},
+		Data: map[string][]byte{
+			"color": []byte("yellow"),
+		},
+	}
+
+	cluster := testutils.GenerateCluster("", "")
+	clusterKey, err := client.ObjectKeyFromObject(cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	workloadResources := map[client.ObjectKey][]runtime.Object{
+		clusterKey: {secret},
+	}
+
+	for _, format := range []base.DiffFormat{base.DiffFormatYAML, base.DiffFormatJSONPatch, base.DiffFormatMergePatch} {
+		format := format
+
+		testEnv := testutils.NewTestEnv(ctx, t, workloadResources, cluster)
+		toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+			DiffFormat:           format,
+			RestConfig:           testEnv.RestConfig,
+			WorkloadClientGetter: testEnv.WorkloadClientGetter,
+		}
+		tool := &base.Tool{}
+		tool.Configure(toolConfig)
+
+		desired := secret.DeepCopy()
+		desired.Data["shape"] = []byte("square")
+		unstructuredSecret := new(unstructured.Unstructured)
+		unstructuredContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+		g.Expect(err).NotTo(HaveOccurred())
+		unstructuredSecret.SetUnstructuredContent(unstructuredContent)
+		unstructuredSecret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+		g.Expect(tool.WorkloadPatchOrCreateUnstructured(ctx, cluster, unstructuredSecret)).To(Succeed())
+
+		output := tool.GetOutputFor(cluster)
+		g.Expect(output).NotTo(ContainSubstring("yellow"))
+		g.Expect(output).NotTo(ContainSubstring("square"))
+	}
+}
+
+func TestTool_ForEachClusterBoundedConcurrency(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	const numClusters = 50
+
+	testClusters := make([]runtime.Object, 0, numClusters)
+	for i := 0; i < numClusters; i++ {
+		testClusters = append(testClusters, testutils.GenerateCluster("", ""))
+	}
+
+	fakeEnv := testutils.NewFakeEnv(ctx, t, nil, testClusters...)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		Concurrency:          5,
+		MgmtClient:           fakeEnv.MgmtClient,
+		WorkloadClientGetter: fakeEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	var (
+		mu          sync.Mutex
+		visited     int
+		inFlight    int
+		maxInFlight int
+	)
+
+	err := tool.ForEachCluster(ctx, func(_ context.Context, c *clusterv1.Cluster) error {
+		mu.Lock()
+		visited++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		fmt.Fprintf(tool.GetBufferFor(c), "visited\n")
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(visited).To(Equal(numClusters))
+	g.Expect(maxInFlight).To(BeNumerically("<=", toolConfig.Concurrency))
+
+	clusters, err := tool.GetClusters(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, c := range clusters {
+		g.Expect(tool.HasError(c)).To(BeFalse())
+		g.Expect(tool.GetOutputFor(c)).To(Equal("visited\n"))
+	}
+}
+
+func TestTool_ForEachClusterCollectsErrorsWithoutFailFast(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	testClusters := []runtime.Object{
+		testutils.GenerateCluster("", "a"),
+		testutils.GenerateCluster("", "b"),
+		testutils.GenerateCluster("", "c"),
+	}
+
+	fakeEnv := testutils.NewFakeEnv(ctx, t, nil, testClusters...)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		MgmtClient:           fakeEnv.MgmtClient,
+		WorkloadClientGetter: fakeEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	err := tool.ForEachCluster(ctx, func(_ context.Context, c *clusterv1.Cluster) error {
+		if c.Name == "a" {
+			return fmt.Errorf("boom") //nolint:goerr113
+		}
+
+		return nil
+	}, base.WithParallelism(2))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	clusters, err := tool.GetClusters(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, c := range clusters {
+		if c.Name == "a" {
+			g.Expect(tool.HasError(c)).To(BeTrue())
+
+			continue
+		}
+
+		g.Expect(tool.HasError(c)).To(BeFalse())
+	}
+}
+
+func TestTool_ForEachClusterFailFastCancelsRemainingClusters(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	testClusters := []runtime.Object{
+		testutils.GenerateCluster("", "a"),
+		testutils.GenerateCluster("", "b"),
+	}
+
+	fakeEnv := testutils.NewFakeEnv(ctx, t, nil, testClusters...)
+	toolConfig := &base.ToolConfig{ //nolint:exhaustivestruct
+		MgmtClient:           fakeEnv.MgmtClient,
+		WorkloadClientGetter: fakeEnv.WorkloadClientGetter,
+	}
+	tool := &base.Tool{}
+	tool.Configure(toolConfig)
+
+	err := tool.ForEachCluster(ctx, func(clusterCtx context.Context, c *clusterv1.Cluster) error {
+		if c.Name == "a" {
+			return fmt.Errorf("boom") //nolint:goerr113
+		}
+
+		<-clusterCtx.Done()
+
+		return clusterCtx.Err()
+	}, base.WithParallelism(2), base.WithFailFast(true))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	clusters, err := tool.GetClusters(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, c := range clusters {
+		g.Expect(tool.HasError(c)).To(BeTrue())
+	}
+}
+
 func TestTool_TestGetClustersNone(t *testing.T) {
 	t.Parallel()
 	g := NewWithT(t)
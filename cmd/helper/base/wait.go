@@ -0,0 +1,320 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// waitBackoff is the exponential-backoff-with-jitter schedule WorkloadWait polls
+// readiness on.
+var waitBackoff = wait.Backoff{ //nolint:exhaustivestruct
+	Duration: 250 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.3,
+	Steps:    30,
+	Cap:      15 * time.Second,
+}
+
+// WorkloadWait blocks until obj reaches a ready condition on the workload
+// cluster, using a kind-specific predicate (Pod, Deployment, DaemonSet,
+// StatefulSet, Job, Service, PersistentVolumeClaim and CustomResourceDefinition
+// are handled specially; anything else is considered ready once it can be
+// fetched). It polls with exponential backoff and jitter, honoring ctx
+// cancellation and timeout (a non-positive timeout waits until ctx is done).
+// Progress is streamed into the per-cluster buffer. Under DryRun() the wait is
+// skipped and a "(Dry Run) Would wait for …" line is recorded instead.
+func (t *Tool) WorkloadWait(
+	ctx context.Context,
+	c *clusterv1.Cluster,
+	obj controllerutil.Object,
+	timeout time.Duration,
+) error {
+	// t.scheme, needed for GVKForObject below, is populated by ManagementClient.
+	if _, err := t.ManagementClient(); err != nil {
+		return err
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, t.scheme)
+	if err != nil {
+		return err
+	}
+
+	if t.DryRun() {
+		fmt.Fprintf(t.GetBufferFor(c), "(Dry Run) Would wait for %s %s to become ready\n", gvk.Kind, ObjectToName(obj))
+
+		return nil
+	}
+
+	waitCtx := ctx
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to create object key: %w", err)
+	}
+
+	predicate := readinessPredicateFor(gvk.Kind)
+
+	check := func() (bool, error) {
+		ready, err := predicate(waitCtx, t, c, obj, key)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				fmt.Fprintf(t.GetBufferFor(c), "… waiting for %s %s to exist\n", gvk.Kind, ObjectToName(obj))
+
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		if !ready {
+			fmt.Fprintf(t.GetBufferFor(c), "… waiting for %s %s to become ready\n", gvk.Kind, ObjectToName(obj))
+		}
+
+		return ready, nil
+	}
+
+	// Once the backoff reaches its Cap, Step keeps returning the (jittered) cap,
+	// so polling continues until the object is ready or waitCtx is done.
+	backoff := waitBackoff
+
+	for ready := false; !ready && err == nil; {
+		if ready, err = check(); err == nil && !ready {
+			select {
+			case <-waitCtx.Done():
+				err = waitCtx.Err()
+			case <-time.After(backoff.Step()):
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed waiting for %s %s to become ready: %w", gvk.Kind, ObjectToName(obj), err)
+	}
+
+	fmt.Fprintf(t.GetBufferFor(c), "✅ %s %s is ready\n", gvk.Kind, ObjectToName(obj))
+
+	return nil
+}
+
+// WorkloadWaitAll calls WorkloadWait for every object in objects, in order,
+// returning on the first error.
+func (t *Tool) WorkloadWaitAll(
+	ctx context.Context,
+	c *clusterv1.Cluster,
+	objects []controllerutil.Object,
+	timeout time.Duration,
+) error {
+	for _, obj := range objects {
+		if err := t.WorkloadWait(ctx, c, obj, timeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type readinessPredicate func(ctx context.Context, t *Tool, c *clusterv1.Cluster, obj controllerutil.Object, key client.ObjectKey) (bool, error)
+
+func readinessPredicateFor(kind string) readinessPredicate {
+	switch kind {
+	case "Pod":
+		return podReady
+	case "Deployment":
+		return deploymentReady
+	case "DaemonSet":
+		return daemonSetReady
+	case "StatefulSet":
+		return statefulSetReady
+	case "Job":
+		return jobReady
+	case "Service":
+		return serviceReady
+	case "PersistentVolumeClaim":
+		return pvcReady
+	case "CustomResourceDefinition":
+		return crdReady
+	default:
+		return existsReady
+	}
+}
+
+func podReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	pod := new(corev1.Pod)
+	if err := t.WorkloadGet(ctx, c, key, pod); err != nil {
+		return false, err
+	}
+
+	if pod.Status.Phase != corev1.PodRunning {
+		return false, nil
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue, nil
+		}
+	}
+
+	return false, nil
+}
+
+func deploymentReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	d := new(appsv1.Deployment)
+	if err := t.WorkloadGet(ctx, c, key, d); err != nil {
+		return false, err
+	}
+
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == desired &&
+		d.Status.Replicas == desired &&
+		d.Status.AvailableReplicas == desired &&
+		d.Status.UnavailableReplicas == 0, nil
+}
+
+func daemonSetReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	ds := new(appsv1.DaemonSet)
+	if err := t.WorkloadGet(ctx, c, key, ds); err != nil {
+		return false, err
+	}
+
+	return ds.Status.NumberReady == ds.Status.DesiredNumberScheduled &&
+		ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled, nil
+}
+
+func statefulSetReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	ss := new(appsv1.StatefulSet)
+	if err := t.WorkloadGet(ctx, c, key, ss); err != nil {
+		return false, err
+	}
+
+	desired := int32(1)
+	if ss.Spec.Replicas != nil {
+		desired = *ss.Spec.Replicas
+	}
+
+	return ss.Status.ReadyReplicas == desired &&
+		ss.Status.UpdateRevision == ss.Status.CurrentRevision, nil
+}
+
+func jobReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	job := new(batchv1.Job)
+	if err := t.WorkloadGet(ctx, c, key, job); err != nil {
+		return false, err
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobComplete {
+			return cond.Status == corev1.ConditionTrue, nil
+		}
+	}
+
+	return false, nil
+}
+
+func serviceReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	svc := new(corev1.Service)
+	if err := t.WorkloadGet(ctx, c, key, svc); err != nil {
+		return false, err
+	}
+
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		return len(svc.Status.LoadBalancer.Ingress) > 0, nil
+	}
+
+	endpoints := new(corev1.Endpoints)
+	if err := t.WorkloadGet(ctx, c, key, endpoints); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func pvcReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	pvc := new(corev1.PersistentVolumeClaim)
+	if err := t.WorkloadGet(ctx, c, key, pvc); err != nil {
+		return false, err
+	}
+
+	return pvc.Status.Phase == corev1.ClaimBound, nil
+}
+
+func crdReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, _ controllerutil.Object, key client.ObjectKey) (bool, error) {
+	crd := new(apiextensionsv1.CustomResourceDefinition)
+	if err := t.WorkloadGet(ctx, c, key, crd); err != nil {
+		return false, err
+	}
+
+	established, namesAccepted := false, false
+
+	for _, cond := range crd.Status.Conditions {
+		switch cond.Type {
+		case apiextensionsv1.Established:
+			established = cond.Status == apiextensionsv1.ConditionTrue
+		case apiextensionsv1.NamesAccepted:
+			namesAccepted = cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+
+	return established && namesAccepted, nil
+}
+
+func existsReady(ctx context.Context, t *Tool, c *clusterv1.Cluster, obj controllerutil.Object, key client.ObjectKey) (bool, error) {
+	empty := obj.DeepCopyObject().(controllerutil.Object) //nolint:forcetypeassert
+	err := t.WorkloadGet(ctx, c, key, empty)
+
+	if err == nil {
+		return true, nil
+	}
+
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	return false, err
+}